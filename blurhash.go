@@ -0,0 +1,162 @@
+package notescan
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+)
+
+const base83Chars = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// Blurhash computes a compact base83-encoded preview string for img, as
+// per the blurhash spec (https://github.com/woltapp/blurhash). It lets a
+// gallery show a tiny placeholder for a scanned page while the full
+// image loads.
+func Blurhash(img image.Image, xComponents, yComponents int) (string, error) {
+	if xComponents < 1 || xComponents > 9 || yComponents < 1 || yComponents > 9 {
+		return "", fmt.Errorf("blurhash: components must be between 1 and 9")
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return "", fmt.Errorf("blurhash: image has no pixels")
+	}
+
+	factors := make([][3]float64, xComponents*yComponents)
+	for j := 0; j < yComponents; j++ {
+		for i := 0; i < xComponents; i++ {
+			factors[j*xComponents+i] = blurhashBasis(img, b, w, h, i, j)
+		}
+	}
+
+	return encodeBlurhash(factors, xComponents, yComponents), nil
+}
+
+// blurhashBasis computes the DCT basis coefficient a_{i,j}, the average
+// of each linear sRGB channel weighted by cos(pi*i*x/w)*cos(pi*j*y/h),
+// doubled for every component except the DC one (i == 0 && j == 0).
+func blurhashBasis(img image.Image, b image.Rectangle, w, h, i, j int) [3]float64 {
+	var r, g, bl float64
+
+	normalisation := 2.0
+	if i == 0 && j == 0 {
+		normalisation = 1.0
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			basis := math.Cos(math.Pi*float64(i)*float64(x)/float64(w)) *
+				math.Cos(math.Pi*float64(j)*float64(y)/float64(h))
+
+			c, err := convertColor(img.At(b.Min.X+x, b.Min.Y+y))
+			if err != nil {
+				continue
+			}
+			r += basis * srgbToLinear(c.R)
+			g += basis * srgbToLinear(c.G)
+			bl += basis * srgbToLinear(c.B)
+		}
+	}
+
+	scale := normalisation / float64(w*h)
+	return [3]float64{r * scale, g * scale, bl * scale}
+}
+
+// encodeBlurhash lays out factors (DC first, then AC in row-major order)
+// into the standard blurhash string: size flag, quantised max AC
+// magnitude, the DC component, then each AC component.
+func encodeBlurhash(factors [][3]float64, xComponents, yComponents int) string {
+	var sb strings.Builder
+
+	sizeFlag := (xComponents - 1) + (yComponents-1)*9
+	sb.WriteString(base83Encode(sizeFlag, 1))
+
+	maximumValue := 1.0
+	if len(factors) > 1 {
+		actualMaximumValue := 0.0
+		for _, f := range factors[1:] {
+			for _, v := range f {
+				if math.Abs(v) > actualMaximumValue {
+					actualMaximumValue = math.Abs(v)
+				}
+			}
+		}
+
+		quantisedMaximumValue := int(clampFloat(math.Floor(actualMaximumValue*166-0.5), 0, 82))
+		maximumValue = float64(quantisedMaximumValue+1) / 166.0
+		sb.WriteString(base83Encode(quantisedMaximumValue, 1))
+	} else {
+		sb.WriteString(base83Encode(0, 1))
+	}
+
+	sb.WriteString(base83Encode(encodeDC(factors[0]), 4))
+
+	for _, f := range factors[1:] {
+		sb.WriteString(base83Encode(encodeAC(f, maximumValue), 2))
+	}
+
+	return sb.String()
+}
+
+// encodeDC packs the DC (average color) component as 8-bit sRGB.
+func encodeDC(rgb [3]float64) int {
+	r := linearToSRGBInt(rgb[0])
+	g := linearToSRGBInt(rgb[1])
+	b := linearToSRGBInt(rgb[2])
+	return r<<16 | g<<8 | b
+}
+
+// encodeAC quantises an AC component to 0-18 per channel, relative to
+// maximumValue.
+func encodeAC(rgb [3]float64, maximumValue float64) int {
+	quantR := int(clampFloat(math.Floor(signPow(rgb[0]/maximumValue, 0.5)*9+9.5), 0, 18))
+	quantG := int(clampFloat(math.Floor(signPow(rgb[1]/maximumValue, 0.5)*9+9.5), 0, 18))
+	quantB := int(clampFloat(math.Floor(signPow(rgb[2]/maximumValue, 0.5)*9+9.5), 0, 18))
+	return quantR*19*19 + quantG*19 + quantB
+}
+
+func signPow(val, exp float64) float64 {
+	sign := 1.0
+	if val < 0 {
+		sign = -1.0
+	}
+	return sign * math.Pow(math.Abs(val), exp)
+}
+
+// linearToSRGBInt gamma-encodes a linear [0,1] channel value to 0-255.
+func linearToSRGBInt(value float64) int {
+	v := clampFloat(value, 0, 1)
+	if v <= 0.0031308 {
+		return int(v*12.92*255 + 0.5)
+	}
+	return int((1.055*math.Pow(v, 1.0/2.4)-0.055)*255 + 0.5)
+}
+
+func clampFloat(v, lo, hi float64) float64 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+func base83Encode(value, length int) string {
+	buf := make([]byte, length)
+	for i := 1; i <= length; i++ {
+		digit := (value / pow83(length-i)) % 83
+		buf[i-1] = base83Chars[digit]
+	}
+	return string(buf)
+}
+
+func pow83(n int) int {
+	result := 1
+	for i := 0; i < n; i++ {
+		result *= 83
+	}
+	return result
+}
@@ -0,0 +1,90 @@
+package notescan
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func flatColorImage(w, h int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestBlurhashLengthAndCharset(t *testing.T) {
+	img := flatColorImage(20, 10, color.RGBA{R: 200, G: 100, B: 50, A: 255})
+
+	hash, err := Blurhash(img, 4, 3)
+	if err != nil {
+		t.Fatalf("Blurhash: %v", err)
+	}
+
+	// 1 size flag + 1 quantised max AC + 4 DC + 2 per remaining component
+	want := 1 + 1 + 4 + 2*(4*3-1)
+	if len(hash) != want {
+		t.Fatalf("got length %d, want %d", len(hash), want)
+	}
+
+	for _, r := range hash {
+		if !strings.ContainsRune(base83Chars, r) {
+			t.Fatalf("hash contains non-base83 character %q", r)
+		}
+	}
+}
+
+func TestBlurhashDeterministic(t *testing.T) {
+	img := flatColorImage(20, 10, color.RGBA{R: 10, G: 220, B: 90, A: 255})
+
+	first, err := Blurhash(img, 4, 3)
+	if err != nil {
+		t.Fatalf("Blurhash: %v", err)
+	}
+	second, err := Blurhash(img, 4, 3)
+	if err != nil {
+		t.Fatalf("Blurhash: %v", err)
+	}
+
+	if first != second {
+		t.Fatalf("Blurhash not deterministic: %q vs %q", first, second)
+	}
+}
+
+func TestBlurhashRejectsOutOfRangeComponents(t *testing.T) {
+	img := flatColorImage(4, 4, color.White)
+
+	cases := [][2]int{{0, 3}, {10, 3}, {4, 0}, {4, 10}}
+	for _, c := range cases {
+		if _, err := Blurhash(img, c[0], c[1]); err == nil {
+			t.Errorf("Blurhash(%d, %d) should return an error", c[0], c[1])
+		}
+	}
+}
+
+func TestBlurhashRejectsEmptyImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 0, 0))
+	if _, err := Blurhash(img, 4, 3); err == nil {
+		t.Fatal("Blurhash on an empty image should return an error")
+	}
+}
+
+func TestBase83EncodeRoundTrip(t *testing.T) {
+	for _, v := range []int{0, 1, 42, 82, 82*83 + 82} {
+		s := base83Encode(v, 2)
+		if len(s) != 2 {
+			t.Fatalf("base83Encode(%d, 2) length = %d, want 2", v, len(s))
+		}
+		got := 0
+		for _, r := range s {
+			got = got*83 + strings.IndexRune(base83Chars, r)
+		}
+		if got != v {
+			t.Fatalf("base83Encode(%d, 2) round-trips to %d", v, got)
+		}
+	}
+}
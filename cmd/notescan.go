@@ -4,9 +4,6 @@ import (
 	"flag"
 	"fmt"
 	"image"
-	_ "image/jpeg"
-	"image/png"
-	_ "image/png"
 	"log"
 	"os"
 	"runtime/pprof"
@@ -29,6 +26,14 @@ var (
 	profileType = flag.String("profileType", "", "Type of profiling to do.")
 	suffix = flag.String("suffix", "_processed", "Suffix in the output filename.")
 	gif = flag.Bool("gif", false, "Should the output file format be gif.")
+	pdf = flag.String("pdf", "", "Collect all input files into a single multi-page PDF at this path, instead of one output file per input.")
+	blurhash = flag.Bool("blurhash", false, "Print a blurhash preview string alongside the output filename.")
+	preprocess = flag.Bool("preprocess", false, "Run the denoise/adjust/stretch/deskew pipeline on each input before Shrink.")
+)
+
+const (
+	blurhashXComponents = 4
+	blurhashYComponents = 3
 )
 
 func Usage() {
@@ -58,6 +63,13 @@ func main() {
 		return
 	}
 
+	if *pdf != "" {
+		if err := runPDF(files, &opt); err != nil {
+			fmt.Printf("[%v]\n", err)
+		}
+		return
+	}
+
 	// process each input file asynchronously
 	wg := sync.WaitGroup{}
 	for _, f := range files {
@@ -75,6 +87,49 @@ func main() {
 	return
 }
 
+// process every input file and collect the results into a single PDF
+func runPDF(files []string, opt *notescan.Option) error {
+	pages := make([]image.Image, len(files))
+	errs := make([]error, len(files))
+
+	wg := sync.WaitGroup{}
+	for i, f := range files {
+		wg.Add(1)
+		go func(i int, file string) {
+			defer wg.Done()
+			log.Printf("Shrink: [%s]\n", file)
+
+			in, err := loadImage(file)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			in, err = maybePreProcess(in)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			pages[i], errs[i] = notescan.Shrink(in, opt)
+		}(i, f)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := notescan.OutputPDF(*pdf, pages); err != nil {
+		return err
+	}
+
+	log.Printf("Generated: [%s]\n", *pdf)
+	return nil
+}
+
 // perform file conversion
 func run(f string, opt *notescan.Option) error {
 	log.Printf("Shrink: [%s]\n", f)
@@ -85,6 +140,11 @@ func run(f string, opt *notescan.Option) error {
 		return err
 	}
 
+	in, err = maybePreProcess(in)
+	if err != nil {
+		return err
+	}
+
 	// compress image
 	shrink, err := notescan.Shrink(in, opt)
 	if err != nil {
@@ -114,25 +174,38 @@ func run(f string, opt *notescan.Option) error {
 
 	if err == nil {
 		log.Printf("Generated: [%s]\n", output)
+		printBlurhash(output, shrink)
 	}
 
 	return err
 }
 
-// load input image
-func loadImage(f string) (image.Image, error) {
-	file, err := os.Open(f)
-	if err != nil {
-		return nil, err
+// print a blurhash preview string for the processed page, if requested
+func printBlurhash(output string, img image.Image) {
+	if !*blurhash {
+		return
 	}
-	defer file.Close()
 
-	img, _, err := image.Decode(file)
+	hash, err := notescan.Blurhash(img, blurhashXComponents, blurhashYComponents)
 	if err != nil {
-		return nil, err
+		log.Printf("Blurhash failed for [%s]: %v\n", output, err)
+		return
 	}
 
-	return img, nil
+	log.Printf("Blurhash: [%s] %s\n", output, hash)
+}
+
+// load input image, correcting for EXIF orientation on JPEGs
+func loadImage(f string) (image.Image, error) {
+	return notescan.LoadImage(f)
+}
+
+// run the denoise/adjust/stretch/deskew pipeline on img if requested
+func maybePreProcess(img image.Image) (image.Image, error) {
+	if !*preprocess {
+		return img, nil
+	}
+	return notescan.PreProcess(img, notescan.DefaultPreProcessOption())
 }
 
 // profiling
@@ -0,0 +1,216 @@
+package notescan
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+)
+
+// LoadImage decodes the image at path and, for JPEGs carrying an EXIF
+// orientation tag, rotates/flips the result so the pixel data matches the
+// visual orientation. Phone camera scans almost always carry this tag, and
+// without correcting for it Shrink would sample and quantize the image
+// sideways or upside down.
+func LoadImage(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	orientation, _ := readOrientation(file)
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return applyOrientation(img, orientation), nil
+}
+
+// readOrientation reads the EXIF orientation tag (1-8) from the APP1
+// segment of a JPEG. It returns 1 (normal) if r isn't a JPEG or carries no
+// EXIF orientation tag.
+func readOrientation(r io.Reader) (int, error) {
+	var soi [2]byte
+	if _, err := io.ReadFull(r, soi[:]); err != nil {
+		return 1, err
+	}
+	if soi[0] != 0xFF || soi[1] != 0xD8 {
+		return 1, fmt.Errorf("exif: not a JPEG file")
+	}
+
+	for {
+		var marker [2]byte
+		if _, err := io.ReadFull(r, marker[:]); err != nil {
+			return 1, err
+		}
+		if marker[0] != 0xFF {
+			return 1, fmt.Errorf("exif: invalid marker")
+		}
+		if marker[1] == 0x01 || (marker[1] >= 0xD0 && marker[1] <= 0xD8) {
+			continue
+		}
+		if marker[1] == 0xDA {
+			return 1, nil
+		}
+
+		var segLenBuf [2]byte
+		if _, err := io.ReadFull(r, segLenBuf[:]); err != nil {
+			return 1, err
+		}
+		segLen := int(segLenBuf[0])<<8 | int(segLenBuf[1])
+		if segLen < 2 {
+			return 1, fmt.Errorf("exif: invalid segment length")
+		}
+
+		payload := make([]byte, segLen-2)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return 1, err
+		}
+
+		if marker[1] == 0xE1 && len(payload) > 6 && string(payload[:6]) == "Exif\x00\x00" {
+			return parseExifOrientation(payload[6:])
+		}
+	}
+}
+
+// parseExifOrientation walks the IFD0 entries of a TIFF-formatted EXIF
+// block looking for tag 0x0112 (Orientation).
+func parseExifOrientation(tiff []byte) (int, error) {
+	if len(tiff) < 8 {
+		return 1, fmt.Errorf("exif: short tiff header")
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1, fmt.Errorf("exif: unknown byte order")
+	}
+
+	ifdOffset := order.Uint32(tiff[4:8])
+	if int(ifdOffset)+2 > len(tiff) {
+		return 1, fmt.Errorf("exif: ifd offset out of range")
+	}
+
+	count := order.Uint16(tiff[ifdOffset : ifdOffset+2])
+	entries := tiff[ifdOffset+2:]
+
+	for i := 0; i < int(count); i++ {
+		if (i+1)*12 > len(entries) {
+			break
+		}
+		entry := entries[i*12 : i*12+12]
+		tag := order.Uint16(entry[0:2])
+		if tag == 0x0112 {
+			return int(order.Uint16(entry[8:10])), nil
+		}
+	}
+
+	return 1, nil
+}
+
+// applyOrientation rotates/flips img according to the EXIF orientation
+// values 1-8. Unknown values are treated as 1 (no change).
+func applyOrientation(img image.Image, orientation int) image.Image {
+	switch orientation {
+	case 2:
+		return flipHorizontal(img)
+	case 3:
+		return rotate180(img)
+	case 4:
+		return flipVertical(img)
+	case 5:
+		return flipHorizontal(rotate90(img))
+	case 6:
+		return rotate90(img)
+	case 7:
+		return flipHorizontal(rotate270(img))
+	case 8:
+		return rotate270(img)
+	default:
+		return img
+	}
+}
+
+// rotate90 rotates img 90 degrees clockwise.
+func rotate90(img image.Image) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, h, w))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dst.Set(h-1-y, x, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// rotate270 rotates img 90 degrees counter-clockwise.
+func rotate270(img image.Image) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, h, w))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dst.Set(y, w-1-x, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// rotate180 rotates img 180 degrees.
+func rotate180(img image.Image) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dst.Set(w-1-x, h-1-y, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// flipHorizontal mirrors img across the vertical axis.
+func flipHorizontal(img image.Image) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dst.Set(w-1-x, y, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// flipVertical mirrors img across the horizontal axis.
+func flipVertical(img image.Image) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dst.Set(x, h-1-y, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
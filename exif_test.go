@@ -0,0 +1,195 @@
+package notescan
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+)
+
+// pixelAt returns a color unique to (x, y) so orientation transforms can be
+// checked by coordinate, not just by shape.
+func pixelAt(x, y int) color.RGBA {
+	return color.RGBA{R: uint8(x * 40), G: uint8(y * 40), B: 77, A: 255}
+}
+
+func newOrientationTestImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, pixelAt(x, y))
+		}
+	}
+	return img
+}
+
+func TestApplyOrientation(t *testing.T) {
+	const w, h = 3, 2 // asymmetric so a width/height swap bug shows up
+	src := newOrientationTestImage(w, h)
+
+	cases := []struct {
+		orientation int
+		outW, outH  int
+		// srcCoord maps a destination (dx, dy) back to the source (x, y)
+		// it should have come from, derived independently from the
+		// composition applyOrientation's switch uses for this case.
+		srcCoord func(dx, dy int) (int, int)
+	}{
+		{1, w, h, func(dx, dy int) (int, int) { return dx, dy }},
+		{2, w, h, func(dx, dy int) (int, int) { return w - 1 - dx, dy }},
+		{3, w, h, func(dx, dy int) (int, int) { return w - 1 - dx, h - 1 - dy }},
+		{4, w, h, func(dx, dy int) (int, int) { return dx, h - 1 - dy }},
+		{5, h, w, func(dx, dy int) (int, int) { return dy, dx }},
+		{6, h, w, func(dx, dy int) (int, int) { return dy, h - 1 - dx }},
+		{7, h, w, func(dx, dy int) (int, int) { return w - 1 - dy, h - 1 - dx }},
+		{8, h, w, func(dx, dy int) (int, int) { return w - 1 - dy, dx }},
+		{0, w, h, func(dx, dy int) (int, int) { return dx, dy }}, // unknown value treated as 1
+	}
+
+	for _, c := range cases {
+		out := applyOrientation(src, c.orientation)
+		b := out.Bounds()
+		if b.Dx() != c.outW || b.Dy() != c.outH {
+			t.Errorf("orientation %d: got size %dx%d, want %dx%d", c.orientation, b.Dx(), b.Dy(), c.outW, c.outH)
+			continue
+		}
+
+		for dy := 0; dy < c.outH; dy++ {
+			for dx := 0; dx < c.outW; dx++ {
+				sx, sy := c.srcCoord(dx, dy)
+				want := pixelAt(sx, sy)
+				gr, gg, gb, ga := out.At(dx, dy).RGBA()
+				wr, wg, wb, wa := want.RGBA()
+				if gr != wr || gg != wg || gb != wb || ga != wa {
+					t.Errorf("orientation %d: pixel (%d,%d) = %v, want %v (from src (%d,%d))", c.orientation, dx, dy, out.At(dx, dy), want, sx, sy)
+				}
+			}
+		}
+	}
+}
+
+// buildExifTIFF builds a minimal TIFF-formatted EXIF block (as would follow
+// the "Exif\x00\x00" header of an APP1 segment) with a single IFD0 entry:
+// tag 0x0112 (Orientation) = value.
+func buildExifTIFF(order binary.ByteOrder, littleEndian bool, value uint16) []byte {
+	var buf bytes.Buffer
+
+	if littleEndian {
+		buf.WriteString("II")
+	} else {
+		buf.WriteString("MM")
+	}
+	write16 := func(v uint16) {
+		b := make([]byte, 2)
+		order.PutUint16(b, v)
+		buf.Write(b)
+	}
+	write32 := func(v uint32) {
+		b := make([]byte, 4)
+		order.PutUint32(b, v)
+		buf.Write(b)
+	}
+
+	write16(42)     // TIFF magic
+	write32(8)      // IFD0 offset
+	write16(1)      // one entry
+	write16(0x0112) // tag: Orientation
+	write16(3)      // type: SHORT
+	write32(1)      // count
+	write16(value)  // value, left-justified in the 4-byte value field
+	write16(0)      // padding
+	write32(0)      // next IFD offset
+
+	return buf.Bytes()
+}
+
+func TestParseExifOrientation(t *testing.T) {
+	cases := []struct {
+		name         string
+		order        binary.ByteOrder
+		littleEndian bool
+		value        uint16
+	}{
+		{"little-endian (II)", binary.LittleEndian, true, 6},
+		{"big-endian (MM)", binary.BigEndian, false, 8},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tiff := buildExifTIFF(c.order, c.littleEndian, c.value)
+			got, err := parseExifOrientation(tiff)
+			if err != nil {
+				t.Fatalf("parseExifOrientation: %v", err)
+			}
+			if got != int(c.value) {
+				t.Fatalf("got orientation %d, want %d", got, c.value)
+			}
+		})
+	}
+}
+
+func TestParseExifOrientationNoTag(t *testing.T) {
+	// an IFD0 with zero entries: no Orientation tag present
+	var buf bytes.Buffer
+	buf.WriteString("II")
+	b := make([]byte, 2)
+	binary.LittleEndian.PutUint16(b, 42)
+	buf.Write(b)
+	b4 := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b4, 8)
+	buf.Write(b4)
+	binary.LittleEndian.PutUint16(b, 0)
+	buf.Write(b)
+
+	got, err := parseExifOrientation(buf.Bytes())
+	if err != nil {
+		t.Fatalf("parseExifOrientation: %v", err)
+	}
+	if got != 1 {
+		t.Fatalf("got orientation %d, want 1 (default) when no Orientation tag is present", got)
+	}
+}
+
+// buildJPEGWithExifOrientation builds a minimal (non-decodable) JPEG byte
+// stream: SOI, an APP1 segment carrying the given EXIF orientation, and an
+// SOS marker, enough for readOrientation to find the tag without needing a
+// full valid JPEG bitstream.
+func buildJPEGWithExifOrientation(order binary.ByteOrder, littleEndian bool, value uint16) []byte {
+	tiff := buildExifTIFF(order, littleEndian, value)
+
+	var app1 bytes.Buffer
+	app1.WriteString("Exif\x00\x00")
+	app1.Write(tiff)
+
+	var buf bytes.Buffer
+	buf.Write([]byte{0xFF, 0xD8}) // SOI
+	buf.Write([]byte{0xFF, 0xE1}) // APP1 marker
+	segLen := app1.Len() + 2
+	buf.Write([]byte{byte(segLen >> 8), byte(segLen)})
+	buf.Write(app1.Bytes())
+	buf.Write([]byte{0xFF, 0xDA}) // SOS: readOrientation stops here
+
+	return buf.Bytes()
+}
+
+func TestReadOrientation(t *testing.T) {
+	jpegBytes := buildJPEGWithExifOrientation(binary.BigEndian, false, 6)
+	got, err := readOrientation(bytes.NewReader(jpegBytes))
+	if err != nil {
+		t.Fatalf("readOrientation: %v", err)
+	}
+	if got != 6 {
+		t.Fatalf("got orientation %d, want 6", got)
+	}
+}
+
+func TestReadOrientationNotAJPEG(t *testing.T) {
+	got, err := readOrientation(bytes.NewReader([]byte{0x89, 0x50, 0x4E, 0x47}))
+	if err == nil {
+		t.Fatal("expected an error for a non-JPEG input")
+	}
+	if got != 1 {
+		t.Fatalf("got orientation %d, want 1 (default) on error", got)
+	}
+}
@@ -1,113 +1,98 @@
 package notescan
 
 import (
-	"fmt"
 	"image"
 	"image/color"
-	"image/gif"
-	"image/png"
 	"math"
-	"os"
+	"runtime"
+	"sync"
 )
 
-// compressed png output file
-func OutputPNG(f string, img image.Image) error {
-	out, err := os.Create(f)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
+// tileSize is the width of the column strips that convertPixels and
+// apply are split into for parallel processing.
+const tileSize = 512
 
-	var encoder png.Encoder
-	encoder.CompressionLevel = png.BestCompression
-	return encoder.Encode(out, img)
+// colTile is a [start, end) range of columns processed by one worker.
+type colTile struct {
+	start int
+	end   int
 }
 
-var gifPalette color.Palette = nil
-
-// Creation of gif palette to do color reduction
-func setGIFPalette(bg *Pixel, fg Pixels) {
-	gifPalette = make(color.Palette, len(fg)+1)
-	gifPalette[0] = bg.Color()
-	for i, pixel := range fg {
-		gifPalette[i+1] = pixel.Color()
+// tileColumns splits [0, cols) into colTiles of at most size columns.
+func tileColumns(cols, size int) []colTile {
+	tiles := make([]colTile, 0, (cols+size-1)/size)
+	for start := 0; start < cols; start += size {
+		end := start + size
+		if end > cols {
+			end = cols
+		}
+		tiles = append(tiles, colTile{start: start, end: end})
 	}
+	return tiles
 }
 
-// compressed gif output file
-func OutputGIF(f string, img image.Image) error {
-	if gifPalette == nil {
-		return fmt.Errorf("Palette is nil")
-	}
+// runTiled runs work for each tile of [0, cols) across GOMAXPROCS
+// workers and waits for all of them to finish.
+func runTiled(cols int, work func(t colTile)) {
+	tiles := tileColumns(cols, tileSize)
 
-	out, err := os.Create(f)
-	if err != nil {
-		return err
+	workers := runtime.GOMAXPROCS(0)
+	if workers < 1 {
+		workers = 1
 	}
-	defer out.Close()
 
-	opt := &gif.Options{
-		NumColors: len(gifPalette),
-		Quantizer: NewQuantizer(gifPalette),
+	sem := make(chan struct{}, workers)
+	wg := sync.WaitGroup{}
+
+	for _, t := range tiles {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(t colTile) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			work(t)
+		}(t)
 	}
-
-	return gif.Encode(out, img, opt)
-}
-
-// gif quantizer
-type gifQuantizer struct {
-	palette color.Palette
+	wg.Wait()
 }
 
-// create new quantizer
-func NewQuantizer(p color.Palette) *gifQuantizer {
-	q := gifQuantizer{}
-	q.palette = p
-	return &q
-}
-
-// quantizer implementation
-func (q gifQuantizer) Quantize(p color.Palette, img image.Image) color.Palette  {
-	return q.palette
-}
-
-// convert image into pixels
+// convert image into pixels, tiled across GOMAXPROCS workers
 func convertPixels(img image.Image) (Pixels, error) {
 	rect := img.Bounds()
 	cols := rect.Dx()
 	rows := rect.Dy()
 
 	rtn := make(Pixels, cols*rows)
-	idx := 0
 
-	for col := 0; col < cols; col++ {
-		for row := 0; row < rows; row++ {
-			color := img.At(col, row)
-			rtn[idx] = NewPixel(color)
-			idx++
+	runTiled(cols, func(t colTile) {
+		for col := t.start; col < t.end; col++ {
+			base := col * rows
+			for row := 0; row < rows; row++ {
+				rtn[base+row] = NewPixel(img.At(col, row))
+			}
 		}
-	}
+	})
 
 	return rtn, nil
 }
 
-// convert color to RGBA format
+// convert color to RGBA format. Anything outside the fast-pathed types
+// falls back to color.RGBAModel, so e.g. the color.NRGBA that
+// image/png's decoder hands back for any PNG with an alpha channel is
+// still handled instead of erroring out.
 func convertColor(c color.Color) (*color.RGBA, error) {
-	switch c.(type) {
+	switch o := c.(type) {
 	case color.YCbCr:
-		o := c.(color.YCbCr)
 		r, g, b := color.YCbCrToRGB(o.Y, o.Cb, o.Cr)
 		return UIntRGBA(r, g, b), nil
 	case color.RGBA:
-		newColor := c.(color.RGBA)
-		return &newColor, nil
+		return &o, nil
 	case *color.RGBA:
-		newColor := c.(*color.RGBA)
-		return newColor, nil
+		return o, nil
 	default:
+		rgba := color.RGBAModel.Convert(c).(color.RGBA)
+		return &rgba, nil
 	}
-
-	return nil, fmt.Errorf("Not supported color: [%v]", c)
 }
 
 // source: https://www.rapidtables.com/convert/color/rgb-to-hsv.html
@@ -189,6 +174,56 @@ func HSV2RGBA(h, s, v float64) *color.RGBA {
 	return FloatRGBA(r*255.0, g*255.0, b*255.0)
 }
 
+// source: http://www.brucelindbloom.com/index.html?Eqn_RGB_XYZ_Matrix.html
+func srgbToLinear(c uint8) float64 {
+	v := float64(c) / 255.0
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// convert sRGB to CIE XYZ using the sRGB D65 matrix
+func rgb2xyz(r, g, b uint8) (float64, float64, float64) {
+	rl := srgbToLinear(r)
+	gl := srgbToLinear(g)
+	bl := srgbToLinear(b)
+
+	x := rl*0.4124564 + gl*0.3575761 + bl*0.1804375
+	y := rl*0.2126729 + gl*0.7151522 + bl*0.0721750
+	z := rl*0.0193339 + gl*0.1191920 + bl*0.9503041
+	return x, y, z
+}
+
+// f(t) from the CIE XYZ to Lab conversion
+func labF(t float64) float64 {
+	const delta = 6.0 / 29.0
+	if t > delta*delta*delta {
+		return math.Cbrt(t)
+	}
+	return t/(3*delta*delta) + 4.0/29.0
+}
+
+// convert CIE XYZ (D65) to CIE Lab
+func xyz2lab(x, y, z float64) (float64, float64, float64) {
+	const xn, yn, zn = 0.95047, 1.00000, 1.08883
+
+	fx := labF(x / xn)
+	fy := labF(y / yn)
+	fz := labF(z / zn)
+
+	l := 116*fy - 16
+	a := 500 * (fx - fy)
+	b := 200 * (fy - fz)
+	return l, a, b
+}
+
+// RGB2Lab converts 8-bit sRGB to CIE Lab (D65 white point)
+func RGB2Lab(r, g, b uint8) (float64, float64, float64) {
+	x, y, z := rgb2xyz(r, g, b)
+	return xyz2lab(x, y, z)
+}
+
 // create RGBA from float RGB values
 func FloatRGBA(r, g, b float64) *color.RGBA {
 
@@ -0,0 +1,33 @@
+package notescan
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// image/png's decoder returns color.NRGBA for any PNG with an alpha
+// channel; convertColor must handle it instead of erroring out.
+func TestConvertColorNRGBA(t *testing.T) {
+	c := color.NRGBA{R: 10, G: 20, B: 30, A: 128}
+	rgba, err := convertColor(c)
+	if err != nil {
+		t.Fatalf("convertColor(NRGBA): %v", err)
+	}
+	if rgba == nil {
+		t.Fatal("convertColor(NRGBA) returned nil color")
+	}
+}
+
+func TestPreProcessHandlesNRGBA(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			img.Set(x, y, color.NRGBA{R: 200, G: 200, B: 200, A: 255})
+		}
+	}
+
+	if _, err := PreProcess(img, DefaultPreProcessOption()); err != nil {
+		t.Fatalf("PreProcess on NRGBA image: %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package notescan
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"strings"
+
+	"golang.org/x/image/tiff"
+)
+
+// compressed png output file
+func OutputPNG(f string, img image.Image) error {
+	out, err := os.Create(f)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	var encoder png.Encoder
+	encoder.CompressionLevel = png.BestCompression
+	return encoder.Encode(out, img)
+}
+
+// compressed jpeg output file
+func OutputJPEG(f string, img image.Image, quality int) error {
+	out, err := os.Create(f)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	return jpeg.Encode(out, img, &jpeg.Options{Quality: quality})
+}
+
+// compressed tiff output file
+func OutputTIFF(f string, img image.Image) error {
+	out, err := os.Create(f)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	return tiff.Encode(out, img, &tiff.Options{Compression: tiff.Deflate})
+}
+
+// compressed gif output file
+func OutputGIF(f string, img image.Image) error {
+	out, err := os.Create(f)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	return gif.Encode(out, img, nil)
+}
+
+// OutputPDF packs pages into a single PDF file, one page per image, each
+// embedded as a Flate-compressed DeviceRGB image XObject sized to fill
+// its own media box. This lets a whole scanned notebook collapse into
+// one shareable file instead of one PNG/GIF per page. Pages are read
+// through convertColor, so any color.Color model convertColor supports
+// (including the color.NRGBA that comes back from decoding an
+// alpha-channel PNG) works here too.
+func OutputPDF(f string, pages []image.Image) error {
+	if len(pages) == 0 {
+		return fmt.Errorf("no pages to output")
+	}
+
+	out, err := os.Create(f)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	return writePDF(out, pages)
+}
+
+func writePDF(out *os.File, pages []image.Image) error {
+	// object 1: catalog, object 2: pages; each page then contributes
+	// three objects: the page, its content stream and its image XObject.
+	totalObjs := 2 + len(pages)*3
+	offsets := make([]int, totalObjs+1)
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+
+	writeObj := func(id int, body string) {
+		offsets[id] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", id, body)
+	}
+
+	writeStreamObj := func(id int, dict string, data []byte) {
+		offsets[id] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nstream\n", id, dict)
+		buf.Write(data)
+		buf.WriteString("\nendstream\nendobj\n")
+	}
+
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 3+i*3)
+	}
+
+	writeObj(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	writeObj(2, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+
+	for i, page := range pages {
+		pageID := 3 + i*3
+		contentID := pageID + 1
+		imageID := pageID + 2
+
+		b := page.Bounds()
+		w, h := b.Dx(), b.Dy()
+
+		raw := make([]byte, 0, w*h*3)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				c, err := convertColor(page.At(b.Min.X+x, b.Min.Y+y))
+				if err != nil {
+					return err
+				}
+				raw = append(raw, c.R, c.G, c.B)
+			}
+		}
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		if _, err := zw.Write(raw); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+
+		content := fmt.Sprintf("q %d 0 0 %d 0 0 cm /Im%d Do Q", w, h, i)
+
+		writeObj(pageID, fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /XObject << /Im%d %d 0 R >> >> /Contents %d 0 R >>",
+			w, h, i, imageID, contentID))
+
+		writeStreamObj(contentID, fmt.Sprintf("<< /Length %d >>", len(content)), []byte(content))
+
+		imgDict := fmt.Sprintf(
+			"<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode /Length %d >>",
+			w, h, compressed.Len())
+		writeStreamObj(imageID, imgDict, compressed.Bytes())
+	}
+
+	xrefStart := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n", totalObjs+1)
+	buf.WriteString("0000000000 65535 f \n")
+	for id := 1; id <= totalObjs; id++ {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offsets[id])
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF", totalObjs+1, xrefStart)
+
+	_, err := out.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,31 @@
+package notescan
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"testing"
+)
+
+// OutputPDF reads each page through convertColor, so it must accept the
+// color.NRGBA pages that image/png hands back for alpha-channel inputs,
+// not just plain color.RGBA.
+func TestOutputPDFHandlesNRGBA(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			img.Set(x, y, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+		}
+	}
+
+	f, err := os.CreateTemp("", "notescan-*.pdf")
+	if err != nil {
+		t.Fatalf("CreateTemp: %v", err)
+	}
+	defer os.Remove(f.Name())
+	f.Close()
+
+	if err := OutputPDF(f.Name(), []image.Image{img}); err != nil {
+		t.Fatalf("OutputPDF on NRGBA page: %v", err)
+	}
+}
@@ -76,6 +76,33 @@ func (p Pixel) DistanceRGB(src *Pixel) float64 {
 	return all
 }
 
+// get CIE Lab space distance (CIE76 DeltaE)
+func (p Pixel) DistanceLab(src *Pixel) float64 {
+	l1, a1, b1 := RGB2Lab(p.R, p.G, p.B)
+	l2, a2, b2 := RGB2Lab(src.R, src.G, src.B)
+
+	dl := l1 - l2
+	da := a1 - a2
+	db := b1 - b2
+	return math.Sqrt(dl*dl + da*da + db*db)
+}
+
+// ColorSpace selects the color space used for Pixel distance comparisons.
+type ColorSpace int
+
+const (
+	ColorSpaceRGB ColorSpace = iota
+	ColorSpaceLab
+)
+
+// Distance returns the distance between p and src in the given color space.
+func (p Pixel) Distance(src *Pixel, cs ColorSpace) float64 {
+	if cs == ColorSpaceLab {
+		return p.DistanceLab(src)
+	}
+	return p.DistanceRGB(src)
+}
+
 // shift operation
 func (p Pixel) Shift(shift uint) *Pixel {
 	r := uint8((p.R >> shift) << shift)
@@ -170,6 +197,47 @@ func (p Pixels) ToImage(cols, rows int) (image.Image,error) {
 	return img,nil
 }
 
+// create a paletted image, mapping each pixel to its index in palette
+func (p Pixels) ToPaletted(cols, rows int, palette color.Palette) (*image.Paletted, error) {
+	if len(palette) > 256 {
+		return nil, fmt.Errorf("ToPaletted: palette has %d colors, more than the 256 a paletted image can index", len(palette))
+	}
+
+	lookup := make(map[int]uint8, len(palette))
+	for i, c := range palette {
+		lookup[Pack(NewPixel(c))] = uint8(i)
+	}
+
+	img := image.NewPaletted(image.Rect(0, 0, cols, rows), palette)
+
+	idx := 0
+	for col := 0; col < cols; col++ {
+		for row := 0; row < rows; row++ {
+			pix := p[idx]
+			idx++
+
+			colorIdx, ok := lookup[Pack(pix)]
+			if !ok {
+				colorIdx = uint8(palette.Index(pix.Color()))
+			}
+			img.SetColorIndex(col, row, colorIdx)
+		}
+	}
+
+	return img, nil
+}
+
+// paletteFor builds the gif/png color.Palette for a background color and
+// its foreground labels, background first.
+func paletteFor(bg *Pixel, fg Pixels) color.Palette {
+	p := make(color.Palette, len(fg)+1)
+	p[0] = bg.Color()
+	for i, pixel := range fg {
+		p[i+1] = pixel.Color()
+	}
+	return p
+}
+
 // sorting
 func (p Pixels) Sort() error {
 	sort.Slice(p, func(i, j int) bool {
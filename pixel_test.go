@@ -0,0 +1,21 @@
+package notescan
+
+import (
+	"image/color"
+	"testing"
+)
+
+// Option.ForegroundNum has no upper bound, so a palette larger than 256
+// entries is reachable from the CLI; ToPaletted must error instead of
+// silently wrapping the index mod 256.
+func TestToPalettedRejectsOversizedPalette(t *testing.T) {
+	palette := make(color.Palette, 257)
+	for i := range palette {
+		palette[i] = color.RGBA{R: uint8(i), A: 255}
+	}
+
+	p := Pixels{NewPixelRGB(0, 0, 0)}
+	if _, err := p.ToPaletted(1, 1, palette); err == nil {
+		t.Fatal("ToPaletted with a 257-color palette should return an error")
+	}
+}
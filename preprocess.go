@@ -0,0 +1,339 @@
+package notescan
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"sort"
+)
+
+// PreProcessOption controls the individually toggleable steps of
+// PreProcess. Brightness, Contrast and Gamma only take effect when
+// Adjust is true.
+type PreProcessOption struct {
+	Denoise bool
+	Deskew  bool
+
+	Adjust     bool
+	Brightness float64
+	Contrast   float64
+	Gamma      float64
+
+	Stretch bool
+}
+
+func DefaultPreProcessOption() *PreProcessOption {
+	return &PreProcessOption{
+		Denoise:    true,
+		Deskew:     true,
+		Adjust:     true,
+		Brightness: 0.0,
+		Contrast:   1.0,
+		Gamma:      1.0,
+		Stretch:    true,
+	}
+}
+
+// PreProcess runs the configured cleanup steps, in order, over img before
+// it is handed to Shrink: noise removal, brightness/contrast/gamma
+// adjustment, automatic contrast stretch, and skew correction. Each step
+// is skipped when its PreProcessOption flag is false.
+func PreProcess(img image.Image, opt *PreProcessOption) (image.Image, error) {
+	if opt == nil {
+		opt = DefaultPreProcessOption()
+	}
+
+	out := img
+
+	if opt.Denoise {
+		var err error
+		out, err = medianFilter(out)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opt.Adjust {
+		out = adjustLUT(out, opt.Brightness, opt.Contrast, opt.Gamma)
+	}
+
+	if opt.Stretch {
+		var err error
+		out, err = contrastStretch(out)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opt.Deskew {
+		var err error
+		out, err = deskew(out)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return out, nil
+}
+
+// medianFilter applies a 3x3 median filter per RGB channel to remove
+// salt-and-pepper scanner noise while preserving pen strokes. Edge
+// pixels are filtered against a clamped-to-bounds neighborhood.
+func medianFilter(img image.Image) (image.Image, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	at := func(x, y int) (color.Color, error) {
+		if x < 0 {
+			x = 0
+		}
+		if x >= w {
+			x = w - 1
+		}
+		if y < 0 {
+			y = 0
+		}
+		if y >= h {
+			y = h - 1
+		}
+		return img.At(b.Min.X+x, b.Min.Y+y), nil
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var rs, gs, bs [9]uint8
+			i := 0
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					c, _ := at(x+dx, y+dy)
+					rgba, err := convertColor(c)
+					if err != nil {
+						return nil, err
+					}
+					rs[i], gs[i], bs[i] = rgba.R, rgba.G, rgba.B
+					i++
+				}
+			}
+			dst.Set(x, y, UIntRGBA(median9(rs), median9(gs), median9(bs)))
+		}
+	}
+
+	return dst, nil
+}
+
+func median9(v [9]uint8) uint8 {
+	sort.Slice(v[:], func(i, j int) bool { return v[i] < v[j] })
+	return v[4]
+}
+
+// adjustLUT applies a per-channel brightness/contrast/gamma lookup table.
+func adjustLUT(img image.Image, brightness, contrast, gamma float64) image.Image {
+	lut := buildLUT(brightness, contrast, gamma)
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c, err := convertColor(img.At(b.Min.X+x, b.Min.Y+y))
+			if err != nil {
+				continue
+			}
+			dst.Set(x, y, UIntRGBA(lut[c.R], lut[c.G], lut[c.B]))
+		}
+	}
+
+	return dst
+}
+
+func buildLUT(brightness, contrast, gamma float64) [256]uint8 {
+	g := gamma
+	if g <= 0 {
+		g = 1
+	}
+
+	var lut [256]uint8
+	for i := 0; i < 256; i++ {
+		v := float64(i) / 255.0
+		v = (v-0.5)*contrast + 0.5 + brightness
+		v = clamp01(v)
+		v = math.Pow(v, 1.0/g)
+		v = clamp01(v)
+		lut[i] = uint8(math.Floor(v*255.0 + 0.5))
+	}
+	return lut
+}
+
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+// contrastStretch finds the 1st and 99th percentile of the value channel
+// and rescales it to fill [0, 1], improving contrast on washed-out scans.
+func contrastStretch(img image.Image) (image.Image, error) {
+	pixels, err := convertPixels(img)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]float64, len(pixels))
+	for i, p := range pixels {
+		values[i] = p.V
+	}
+
+	lo, hi := percentile(values, 0.01), percentile(values, 0.99)
+	if hi <= lo {
+		return img, nil
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	idx := 0
+	for col := 0; col < w; col++ {
+		for row := 0; row < h; row++ {
+			p := pixels[idx]
+			idx++
+
+			v := clamp01((p.V - lo) / (hi - lo))
+			np := NewPixelHSV(p.H, p.S, v)
+			dst.Set(b.Min.X+col, b.Min.Y+row, np.Color())
+		}
+	}
+
+	return dst, nil
+}
+
+func percentile(values []float64, pct float64) float64 {
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	idx := int(pct * float64(len(sorted)-1))
+	return sorted[idx]
+}
+
+// deskew angle range and step, in degrees, and the value (V) at or below
+// which a pixel counts as dark foreground for the Hough accumulator
+const (
+	deskewMaxAngle = 15.0
+	deskewStep     = 0.5
+	deskewDarkV    = 0.5
+)
+
+// deskew estimates the dominant text-line skew angle via a Hough-style
+// accumulator and rotates the image to make lines horizontal.
+func deskew(img image.Image) (image.Image, error) {
+	pixels, err := convertPixels(img)
+	if err != nil {
+		return nil, err
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	angle := estimateSkewAngle(pixels, w, h)
+	if angle == 0 {
+		return img, nil
+	}
+
+	return rotateAngle(img, -angle), nil
+}
+
+// estimateSkewAngle scans candidate angles in [-deskewMaxAngle,
+// deskewMaxAngle] and returns the one whose Hough accumulator has the
+// most sharply peaked bins, i.e. the angle at which the most foreground
+// pixels fall on the same text-line. bestScore starts at 0 rather than
+// an impossible low value so that a page with no dark pixels at all
+// (e.g. blank, or faint content after Stretch already brightened it) -
+// every candidate then scores 0 - defaults to no rotation instead of
+// locking onto whichever angle happened to be tried first.
+func estimateSkewAngle(pixels Pixels, w, h int) float64 {
+	bestAngle := 0.0
+	bestScore := 0.0
+
+	for deg := -deskewMaxAngle; deg <= deskewMaxAngle; deg += deskewStep {
+		theta := deg * math.Pi / 180.0
+		score := houghPeakiness(pixels, w, h, theta)
+		if score > bestScore {
+			bestScore = score
+			bestAngle = deg
+		}
+	}
+
+	return bestAngle
+}
+
+// houghPeakiness bins foreground pixels by their perpendicular distance
+// (rho) from the origin at angle theta, and returns the variance of the
+// bin counts. A well-aligned angle concentrates foreground pixels into a
+// few heavily populated rho bins (the text lines), giving high variance.
+func houghPeakiness(pixels Pixels, w, h int, theta float64) float64 {
+	sinT, cosT := math.Sin(theta), math.Cos(theta)
+	bins := make(map[int]int)
+
+	idx := 0
+	for col := 0; col < w; col++ {
+		for row := 0; row < h; row++ {
+			p := pixels[idx]
+			idx++
+			if p.V > deskewDarkV {
+				continue
+			}
+			rho := int(math.Round(float64(col)*sinT + float64(row)*cosT))
+			bins[rho]++
+		}
+	}
+
+	if len(bins) == 0 {
+		return 0
+	}
+
+	sum, sumSq, n := 0.0, 0.0, float64(len(bins))
+	for _, c := range bins {
+		v := float64(c)
+		sum += v
+		sumSq += v * v
+	}
+	mean := sum / n
+	return sumSq/n - mean*mean
+}
+
+// rotateAngle rotates img by degrees about its center, sampling with
+// nearest-neighbor and filling uncovered corners with white.
+func rotateAngle(img image.Image, degrees float64) image.Image {
+	theta := degrees * math.Pi / 180.0
+	sinT, cosT := math.Sin(theta), math.Cos(theta)
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	cx, cy := float64(w)/2, float64(h)/2
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	bg := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dx := float64(x) - cx
+			dy := float64(y) - cy
+			sx := dx*cosT - dy*sinT + cx
+			sy := dx*sinT + dy*cosT + cy
+
+			ix, iy := int(math.Round(sx)), int(math.Round(sy))
+			if ix < 0 || ix >= w || iy < 0 || iy >= h {
+				dst.Set(x, y, bg)
+				continue
+			}
+			dst.Set(x, y, img.At(b.Min.X+ix, b.Min.Y+iy))
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,37 @@
+package notescan
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// a near-blank page (no pixel dark enough to register in the Hough
+// accumulator) must not be rotated, since every candidate angle ties at
+// score 0 with nothing to distinguish them.
+func TestDeskewNoRotationOnBlankPage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 200; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	for y := 10; y < 30; y++ {
+		for x := 10; x < 30; x++ {
+			img.Set(x, y, color.Gray{Y: 160})
+		}
+	}
+
+	out, err := PreProcess(img, &PreProcessOption{Deskew: true})
+	if err != nil {
+		t.Fatalf("PreProcess: %v", err)
+	}
+
+	got := out.At(15, 15)
+	want := img.At(15, 15)
+	gr, gg, gb, _ := got.RGBA()
+	wr, wg, wb, _ := want.RGBA()
+	if gr != wr || gg != wg || gb != wb {
+		t.Fatalf("deskew rotated a page with no dark pixels: got %v at (15,15), want %v", got, want)
+	}
+}
@@ -0,0 +1,138 @@
+package notescan
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Quantizer reduces a set of foreground pixels down to k representative
+// palette colors. kMeansQuantizer (the default, see Option.quantizer) and
+// MedianCutQuantizer are the two built-in implementations.
+type Quantizer interface {
+	Quantize(p Pixels, k int) (Pixels, error)
+}
+
+// DeterministicQuantizer is implemented by Quantizer types whose output
+// depends only on the input pixels, not on any random seed. Shrink uses
+// this to skip its random sampling step for such quantizers, since
+// quantizing a different random subset on every call would otherwise
+// undo the determinism they were chosen for.
+type DeterministicQuantizer interface {
+	Quantizer
+	Deterministic() bool
+}
+
+// MedianCutQuantizer is a deterministic alternative to k-means: it
+// repeatedly splits the box with the widest color-channel range in half
+// along that axis until there are k boxes, then takes each box's average
+// color as its palette entry. Unlike kMeansQuantizer it needs no random
+// seed, so (combined with Shrink skipping its sampling step for
+// DeterministicQuantizer) two runs of Shrink over the same image produce
+// the same output palette.
+type MedianCutQuantizer struct{}
+
+// Deterministic reports that MedianCutQuantizer's output depends only on
+// its input pixels, so Shrink can skip random sampling for it.
+func (MedianCutQuantizer) Deterministic() bool { return true }
+
+func (MedianCutQuantizer) Quantize(p Pixels, k int) (Pixels, error) {
+	if k <= 0 {
+		return nil, fmt.Errorf("k must be positive")
+	}
+
+	boxes := []Pixels{append(Pixels{}, p...)}
+	for len(boxes) < k {
+		idx, axis, span := widestBox(boxes)
+		if idx == -1 || span <= 0 {
+			break
+		}
+
+		box := boxes[idx]
+		sortByChannel(box, axis)
+
+		mid := len(box) / 2
+		boxes[idx] = box[:mid]
+		boxes = append(boxes, box[mid:])
+	}
+
+	labels := make(Pixels, len(boxes))
+	for i, box := range boxes {
+		avg, err := box.Average()
+		if err != nil {
+			return nil, err
+		}
+		labels[i] = avg
+	}
+
+	return labels, nil
+}
+
+// widestBox finds the box with the largest range along any single RGB
+// channel, returning its index, that channel (0=R, 1=G, 2=B), and the
+// range itself. It returns idx -1 when no box can be split further.
+func widestBox(boxes []Pixels) (int, int, int) {
+	idx, axis, span := -1, 0, -1
+	for i, box := range boxes {
+		if len(box) < 2 {
+			continue
+		}
+		a, s := channelRange(box)
+		if s > span {
+			idx, axis, span = i, a, s
+		}
+	}
+	return idx, axis, span
+}
+
+// channelRange returns the RGB channel with the widest range in box, and
+// that range.
+func channelRange(box Pixels) (int, int) {
+	minR, maxR := 255, 0
+	minG, maxG := 255, 0
+	minB, maxB := 255, 0
+
+	for _, pix := range box {
+		r, g, b := int(pix.R), int(pix.G), int(pix.B)
+		if r < minR {
+			minR = r
+		}
+		if r > maxR {
+			maxR = r
+		}
+		if g < minG {
+			minG = g
+		}
+		if g > maxG {
+			maxG = g
+		}
+		if b < minB {
+			minB = b
+		}
+		if b > maxB {
+			maxB = b
+		}
+	}
+
+	axis, span := 0, maxR-minR
+	if g := maxG - minG; g > span {
+		axis, span = 1, g
+	}
+	if b := maxB - minB; b > span {
+		axis, span = 2, b
+	}
+	return axis, span
+}
+
+// sortByChannel sorts box in place by the given RGB channel.
+func sortByChannel(box Pixels, axis int) {
+	sort.Slice(box, func(i, j int) bool {
+		switch axis {
+		case 0:
+			return box[i].R < box[j].R
+		case 1:
+			return box[i].G < box[j].G
+		default:
+			return box[i].B < box[j].B
+		}
+	})
+}
@@ -0,0 +1,60 @@
+package notescan
+
+import "testing"
+
+func TestMedianCutQuantizerRejectsNonPositiveK(t *testing.T) {
+	p := Pixels{NewPixelRGB(0, 0, 0), NewPixelRGB(255, 255, 255)}
+
+	if _, err := (MedianCutQuantizer{}).Quantize(p, 0); err == nil {
+		t.Fatal("Quantize(k=0) should return an error")
+	}
+	if _, err := (MedianCutQuantizer{}).Quantize(p, -1); err == nil {
+		t.Fatal("Quantize(k=-1) should return an error")
+	}
+}
+
+// Requesting more colors than there are boxes to split into just stops
+// early once widestBox has nothing left to split (every box down to a
+// single pixel), so Quantize returns fewer than k labels rather than
+// erroring or padding with duplicates.
+func TestMedianCutQuantizerFewerDistinctPixelsThanK(t *testing.T) {
+	p := Pixels{NewPixelRGB(0, 0, 0), NewPixelRGB(255, 255, 255)}
+
+	labels, err := (MedianCutQuantizer{}).Quantize(p, 5)
+	if err != nil {
+		t.Fatalf("Quantize: %v", err)
+	}
+	if len(labels) != len(p) {
+		t.Fatalf("got %d labels, want %d (one per distinct input pixel)", len(labels), len(p))
+	}
+}
+
+func TestMedianCutQuantizerDeterministic(t *testing.T) {
+	p := Pixels{
+		NewPixelRGB(10, 20, 30),
+		NewPixelRGB(200, 100, 50),
+		NewPixelRGB(0, 0, 0),
+		NewPixelRGB(255, 255, 255),
+		NewPixelRGB(128, 64, 200),
+	}
+
+	first, err := (MedianCutQuantizer{}).Quantize(p, 3)
+	if err != nil {
+		t.Fatalf("Quantize: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		again, err := (MedianCutQuantizer{}).Quantize(p, 3)
+		if err != nil {
+			t.Fatalf("Quantize: %v", err)
+		}
+		if len(again) != len(first) {
+			t.Fatalf("run %d: got %d labels, want %d", i, len(again), len(first))
+		}
+		for j := range first {
+			if Pack(again[j]) != Pack(first[j]) {
+				t.Fatalf("run %d: label %d = %v, want %v (MedianCutQuantizer must be deterministic)", i, j, again[j], first[j])
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package notescan
 
 import (
+	"fmt"
 	"image"
 	"math"
 	"math/rand"
@@ -14,6 +15,8 @@ type Option struct {
 	ForegroundNum 		int
 	Shift         		int
 	KMeansIterations	int
+	Quantizer		Quantizer
+	ColorSpace		ColorSpace
 }
 
 func init() {
@@ -36,17 +39,26 @@ func Shrink(img image.Image, op *Option) (image.Image, error) {
 		op = DefaultOption()
 	}
 
+	rect := img.Bounds()
+	cols := rect.Dx()
+	rows := rect.Dy()
+
 	// expand pixels
 	data, err := convertPixels(img)
 	if err != nil {
 		return nil, err
 	}
 
-	// sampling
-	num := int(float64(len(data)) * op.SamplingRate)
-	samples, err := createSample(data, num)
-	if err != nil {
-		return nil, err
+	// sampling: skipped for a DeterministicQuantizer (e.g.
+	// MedianCutQuantizer), since quantizing a different random subset on
+	// every call would undo the determinism it was chosen for.
+	samples := data
+	if dq, ok := op.quantizer().(DeterministicQuantizer); !ok || !dq.Deterministic() {
+		num := int(float64(len(data)) * op.SamplingRate)
+		samples, err = createSample(data, num)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// selection of color
@@ -56,36 +68,38 @@ func Shrink(img image.Image, op *Option) (image.Image, error) {
 	}
 
 	// apply color
-	shrink, err := apply(data, bg, palette, op)
+	shrink, err := apply(data, bg, palette, op, cols, rows)
 	if err != nil {
 		return nil, err
 	}
 
-	// create gif palette
-	setGIFPalette(bg, palette)
-
-	rect := img.Bounds()
-	cols := rect.Dx()
-	rows := rect.Dy()
-
-	return shrink.ToImage(cols, rows)
+	return shrink.ToPaletted(cols, rows, paletteFor(bg, palette))
 }
 
-func apply(data Pixels, bg *Pixel, labels Pixels, op *Option) (Pixels, error) {
-	flag, err := getForegroundMask(data, bg, op)
-	if err != nil {
-		return nil, err
-	}
-
+// apply assigns each pixel its background or nearest palette color,
+// tiled across GOMAXPROCS workers since closest does a linear scan of
+// the palette for every pixel.
+func apply(data Pixels, bg *Pixel, labels Pixels, op *Option, cols, rows int) (Pixels, error) {
 	rtn := make([]*Pixel, len(data))
-	for idx := 0; idx < len(data); idx++ {
-		newPix := bg
-		if flag[idx] {
-			wk := closest(data[idx], labels)
-			newPix = labels[wk]
+
+	runTiled(cols, func(t colTile) {
+		for col := t.start; col < t.end; col++ {
+			base := col * rows
+			for row := 0; row < rows; row++ {
+				idx := base + row
+				pix := data[idx]
+
+				newPix := bg
+				_, ds, dv := pix.DistanceHSV(bg)
+				if dv >= op.Brightness || ds >= op.Saturation {
+					wk := closest(pix, labels, op.ColorSpace)
+					newPix = labels[wk]
+				}
+				rtn[idx] = newPix
+			}
 		}
-		rtn[idx] = newPix
-	}
+	})
+
 	return rtn, nil
 }
 
@@ -108,7 +122,7 @@ func createPalette(p Pixels, op *Option) (*Pixel, Pixels, error) {
 		}
 	}
 
-	labels, err := kmeans(target, op)
+	labels, err := op.quantizer().Quantize(target, op.ForegroundNum-1)
 	if err != nil {
 		return bg, nil, err
 	}
@@ -116,6 +130,15 @@ func createPalette(p Pixels, op *Option) (*Pixel, Pixels, error) {
 	return bg, labels, nil
 }
 
+// quantizer resolves the Quantizer to use, falling back to the
+// built-in k-means implementation when none is configured.
+func (op *Option) quantizer() Quantizer {
+	if op.Quantizer != nil {
+		return op.Quantizer
+	}
+	return kMeansQuantizer{Iterations: op.KMeansIterations, ColorSpace: op.ColorSpace}
+}
+
 func getBackgroundColor(p Pixels, op *Option) (*Pixel, error) {
 	q, err := p.Quantize(op.Shift)
 	if err != nil {
@@ -143,20 +166,30 @@ func getForegroundMask(p Pixels, bg *Pixel, op *Option) ([]bool, error) {
 	return rtn, nil
 }
 
-func kmeans(p Pixels, op *Option) ([]*Pixel, error) {
-	k := op.ForegroundNum - 1
-	itr := op.KMeansIterations
+// kMeansQuantizer adapts the built-in k-means clustering to the
+// Quantizer interface.
+type kMeansQuantizer struct {
+	Iterations int
+	ColorSpace ColorSpace
+}
+
+func (q kMeansQuantizer) Quantize(p Pixels, k int) (Pixels, error) {
+	return kmeans(p, k, q.Iterations, q.ColorSpace)
+}
 
-	labels := make([]*Pixel, k)
-	for i := 0; i < k; i++ {
-		h := float64(i) / float64(k-1)
-		pixel := NewPixelHSV(h, 1, 1)
-		labels[i] = pixel
+func kmeans(p Pixels, k int, itr int, cs ColorSpace) ([]*Pixel, error) {
+	if k <= 0 {
+		return nil, fmt.Errorf("kmeans: k must be positive")
+	}
+	if len(p) == 0 {
+		return nil, fmt.Errorf("kmeans: no pixels to cluster")
 	}
 
+	labels := seedPlusPlus(p, k, cs)
+
 	index := make([]int, len(p))
 	for idx, pix := range p {
-		index[idx] = closest(pix, labels)
+		index[idx] = closest(pix, labels, cs)
 	}
 
 	for idx := 0; idx < itr; idx++ {
@@ -179,7 +212,7 @@ func kmeans(p Pixels, op *Option) ([]*Pixel, error) {
 
 		changes := 0
 		for i, pix := range p {
-			if newIdx := closest(pix, labels); newIdx != index[i] {
+			if newIdx := closest(pix, labels, cs); newIdx != index[i] {
 				changes++
 				index[i] = newIdx
 			}
@@ -193,11 +226,60 @@ func kmeans(p Pixels, op *Option) ([]*Pixel, error) {
 	return labels, nil
 }
 
-func closest(p *Pixel, labels []*Pixel) int {
+// seedPlusPlus picks k initial centers from p using k-means++: the first
+// center is uniform random, and each subsequent center is picked with
+// probability proportional to its squared distance to the nearest
+// already-chosen center. This spreads the initial centers across the
+// actual color distribution instead of the evenly-spaced hues the old
+// seeding used, which converged poorly on pastel colors.
+func seedPlusPlus(p Pixels, k int, cs ColorSpace) []*Pixel {
+	labels := make([]*Pixel, 0, k)
+	labels = append(labels, p[rand.Intn(len(p))])
+
+	weights := make([]float64, len(p))
+	for len(labels) < k {
+		total := 0.0
+		for i, pix := range p {
+			d := nearestDistance(pix, labels, cs)
+			weights[i] = d * d
+			total += weights[i]
+		}
+
+		if total == 0 {
+			labels = append(labels, p[rand.Intn(len(p))])
+			continue
+		}
+
+		target := rand.Float64() * total
+		acc := 0.0
+		chosen := p[len(p)-1]
+		for i, pix := range p {
+			acc += weights[i]
+			if acc >= target {
+				chosen = pix
+				break
+			}
+		}
+		labels = append(labels, chosen)
+	}
+	return labels
+}
+
+func nearestDistance(p *Pixel, labels []*Pixel, cs ColorSpace) float64 {
+	min := math.MaxFloat64
+	for _, label := range labels {
+		if d := p.Distance(label, cs); d < min {
+			min = d
+		}
+	}
+	return min
+}
+
+func closest(p *Pixel, labels []*Pixel, cs ColorSpace) int {
 	idx := -1
 	d := math.MaxFloat64
 	for i := 0; i < len(labels); i++ {
-		val := p.DistanceRGB(labels[i])
+		val := p.Distance(labels[i], cs)
 		if val < d {
 			d = val
 			idx = i
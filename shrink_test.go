@@ -0,0 +1,79 @@
+package notescan
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// kMeansQuantizer must reject k<=0 the same way MedianCutQuantizer does,
+// since Option.ForegroundNum<=1 (a user-settable CLI flag) drives k down
+// to 0 via createPalette's op.ForegroundNum-1.
+func TestKMeansQuantizerRejectsNonPositiveK(t *testing.T) {
+	p := Pixels{NewPixelRGB(0, 0, 0), NewPixelRGB(255, 255, 255)}
+
+	if _, err := (kMeansQuantizer{Iterations: 1}).Quantize(p, 0); err == nil {
+		t.Fatal("Quantize(k=0) should return an error")
+	}
+	if _, err := (kMeansQuantizer{Iterations: 1}).Quantize(p, -1); err == nil {
+		t.Fatal("Quantize(k=-1) should return an error")
+	}
+}
+
+// Shrink must actually deliver the reproducibility MedianCutQuantizer is
+// chosen for: it needs to skip the random sampling step it otherwise
+// applies before quantizing, or two runs on the same image would still
+// quantize two different random subsets and produce different palettes.
+func TestShrinkDeterministicWithMedianCutQuantizer(t *testing.T) {
+	// mostly-white background (an unambiguous majority, so getBackgroundColor's
+	// most-frequent-color tie-break can't itself introduce nondeterminism)
+	// with a few unevenly sized colored foreground blobs, like pen strokes on
+	// a scanned page.
+	img := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 40; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	for y := 2; y < 6; y++ {
+		for x := 2; x < 10; x++ {
+			img.Set(x, y, color.RGBA{R: 255, G: 0, B: 0, A: 255})
+		}
+	}
+	for y := 20; y < 22; y++ {
+		for x := 15; x < 18; x++ {
+			img.Set(x, y, color.RGBA{R: 0, G: 120, B: 200, A: 255})
+		}
+	}
+	for y := 30; y < 35; y++ {
+		for x := 25; x < 30; x++ {
+			img.Set(x, y, color.RGBA{R: 20, G: 180, B: 20, A: 255})
+		}
+	}
+
+	opt := DefaultOption()
+	opt.Quantizer = MedianCutQuantizer{}
+
+	first, err := Shrink(img, opt)
+	if err != nil {
+		t.Fatalf("Shrink: %v", err)
+	}
+	second, err := Shrink(img, opt)
+	if err != nil {
+		t.Fatalf("Shrink: %v", err)
+	}
+
+	fb, sb := first.Bounds(), second.Bounds()
+	if fb != sb {
+		t.Fatalf("bounds differ: %v vs %v", fb, sb)
+	}
+	for y := fb.Min.Y; y < fb.Max.Y; y++ {
+		for x := fb.Min.X; x < fb.Max.X; x++ {
+			fr, fg, fb2, fa := first.At(x, y).RGBA()
+			sr, sg, sb2, sa := second.At(x, y).RGBA()
+			if fr != sr || fg != sg || fb2 != sb2 || fa != sa {
+				t.Fatalf("Shrink not reproducible at (%d,%d): %v vs %v", x, y, first.At(x, y), second.At(x, y))
+			}
+		}
+	}
+}